@@ -0,0 +1,37 @@
+// Package common holds types that are shared across tempodb block encodings.
+package common
+
+// BlockConfig carries the tunables used when cutting a new block, regardless
+// of which encoding is writing it.
+type BlockConfig struct {
+	BloomFP             float64 `yaml:"bloom_filter_false_positive"`
+	BloomShardSizeBytes int     `yaml:"bloom_filter_shard_size_bytes"`
+}
+
+// SearchOptions captures the per-request knobs that control how a block is
+// read during a search, independent of the predicate being evaluated.
+type SearchOptions struct {
+	// ChunkSizeBytes is the size of the chunks read from the backend when
+	// streaming column data.
+	ChunkSizeBytes uint32
+
+	// ReadBufferCount is the number of read buffers to use when reading a block.
+	ReadBufferCount int
+
+	// ReadBufferSize is the size of each read buffer used when reading a block.
+	ReadBufferSize int
+
+	// Predicate is the structured match tree to evaluate against the block.
+	// When nil, the caller's SearchRequest.Tags map is lowered via
+	// PredicateFromTags into an AND of Eq matches.
+	Predicate *SearchPredicate
+
+	// Metrics, if set, is populated in place with per-stage counters and
+	// durations for this search. Callers searching multiple blocks can pass
+	// the same instance to each call to accumulate an aggregate total.
+	Metrics *SearchMetrics
+
+	// Concurrency bounds the number of blocks a MultiBlockSearcher will
+	// search at once. Values <= 0 are treated as 1.
+	Concurrency int
+}
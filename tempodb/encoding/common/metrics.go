@@ -0,0 +1,137 @@
+package common
+
+import "time"
+
+// SearchStage identifies one of the phases a block search passes through.
+// Each constant's identifier mirrors the OpenTelemetry span emitted for the
+// same work (StageBloomCheck for vparquet.BloomCheck, and so on); the string
+// values are shorter names used as the Prometheus "stage" label.
+type SearchStage string
+
+const (
+	StageBloomCheck    SearchStage = "bloom"
+	StageRowGroupPrune SearchStage = "rowgroup"
+	StageColumnRead    SearchStage = "column"
+	StagePredicate     SearchStage = "predicate"
+)
+
+// SearchMetrics accumulates counters and per-stage durations for a single
+// Search call. Callers that want a breakdown of where time and bytes went
+// pass a *SearchMetrics through SearchOptions.Metrics; it is populated in
+// place so that a caller searching multiple blocks can share one instance
+// and get an aggregate across all of them.
+type SearchMetrics struct {
+	BloomChecks int
+	BloomHits   int
+
+	RowGroupsTotal     int
+	RowGroupsInspected int
+
+	ColumnBytesRead int64
+	PagesRead       int
+
+	PredicateEvaluations int
+	PredicateMatches     int
+
+	// BlocksOpened counts the blocks a MultiBlockSearcher actually started
+	// searching, as opposed to ones skipped via block-meta time-range
+	// pruning or never reached because an earlier block satisfied the
+	// request's Limit first.
+	BlocksOpened int
+
+	StageDurations map[SearchStage]time.Duration
+}
+
+// NewSearchMetrics returns a zeroed SearchMetrics ready to be passed to
+// SearchOptions.Metrics.
+func NewSearchMetrics() *SearchMetrics {
+	return &SearchMetrics{
+		StageDurations: make(map[SearchStage]time.Duration),
+	}
+}
+
+// AddStageDuration accumulates d into the running total for stage. It is a
+// no-op on a nil *SearchMetrics so call sites don't need to guard every
+// call with an opts.Metrics != nil check.
+func (m *SearchMetrics) AddStageDuration(stage SearchStage, d time.Duration) {
+	if m == nil {
+		return
+	}
+	if m.StageDurations == nil {
+		m.StageDurations = make(map[SearchStage]time.Duration)
+	}
+	m.StageDurations[stage] += d
+}
+
+// AddBloomCheck records a single bloom filter lookup and whether it hit.
+// It is a no-op on a nil *SearchMetrics.
+func (m *SearchMetrics) AddBloomCheck(hit bool) {
+	if m == nil {
+		return
+	}
+	m.BloomChecks++
+	if hit {
+		m.BloomHits++
+	}
+}
+
+// AddRowGroups accumulates the total row groups considered and the subset
+// that survived pruning. It is a no-op on a nil *SearchMetrics.
+func (m *SearchMetrics) AddRowGroups(total, inspected int) {
+	if m == nil {
+		return
+	}
+	m.RowGroupsTotal += total
+	m.RowGroupsInspected += inspected
+}
+
+// AddColumnRead accumulates bytes and pages fetched for a column read. It is
+// a no-op on a nil *SearchMetrics.
+func (m *SearchMetrics) AddColumnRead(bytesRead int64, pagesRead int) {
+	if m == nil {
+		return
+	}
+	m.ColumnBytesRead += bytesRead
+	m.PagesRead += pagesRead
+}
+
+// AddPredicateEvaluations accumulates the rows evaluated and the subset that
+// matched. It is a no-op on a nil *SearchMetrics.
+func (m *SearchMetrics) AddPredicateEvaluations(evaluated, matched int) {
+	if m == nil {
+		return
+	}
+	m.PredicateEvaluations += evaluated
+	m.PredicateMatches += matched
+}
+
+// AddBlocksOpened accumulates the number of blocks a MultiBlockSearcher
+// actually started searching. It is a no-op on a nil *SearchMetrics.
+func (m *SearchMetrics) AddBlocksOpened(n int) {
+	if m == nil {
+		return
+	}
+	m.BlocksOpened += n
+}
+
+// Merge folds other into m, for combining per-block metrics into a
+// per-request total.
+func (m *SearchMetrics) Merge(other *SearchMetrics) {
+	if m == nil || other == nil {
+		return
+	}
+
+	m.BloomChecks += other.BloomChecks
+	m.BloomHits += other.BloomHits
+	m.RowGroupsTotal += other.RowGroupsTotal
+	m.RowGroupsInspected += other.RowGroupsInspected
+	m.ColumnBytesRead += other.ColumnBytesRead
+	m.PagesRead += other.PagesRead
+	m.PredicateEvaluations += other.PredicateEvaluations
+	m.PredicateMatches += other.PredicateMatches
+	m.BlocksOpened += other.BlocksOpened
+
+	for stage, d := range other.StageDurations {
+		m.AddStageDuration(stage, d)
+	}
+}
@@ -0,0 +1,84 @@
+package common
+
+// Op identifies the comparison performed by a Match leaf in a SearchPredicate
+// tree.
+type Op int
+
+const (
+	// OpEq matches when the column value contains Match.Value as a
+	// substring. This mirrors the legacy Tags map semantics, which Eq is
+	// the lowering target for.
+	OpEq Op = iota
+	// OpNotEq matches when the column value does not contain Match.Value as
+	// a substring.
+	OpNotEq
+	// OpRegex matches when the column value matches the Match.Value regular
+	// expression.
+	OpRegex
+	// OpNotRegex matches when the column value does not match the Match.Value
+	// regular expression.
+	OpNotRegex
+	// OpGreaterThan matches numeric columns whose value is greater than
+	// Match.Value.
+	OpGreaterThan
+	// OpLessThan matches numeric columns whose value is less than
+	// Match.Value.
+	OpLessThan
+)
+
+// Match is a leaf of a SearchPredicate tree that compares a single tag
+// (span or resource attribute, or a well-known column) against a value.
+type Match struct {
+	Key   string
+	Op    Op
+	Value string
+}
+
+// SearchPredicate is a boolean tree of tag matches. Exactly one of And, Or,
+// Not or Match should be set on any given node. And/Or combine their
+// children with the corresponding boolean operator; Not inverts its single
+// child; Match is a leaf comparison.
+//
+// The zero value matches everything, as does a nil *SearchPredicate, which
+// is what a request with no Tags and no explicit Predicate lowers to.
+type SearchPredicate struct {
+	And   []*SearchPredicate
+	Or    []*SearchPredicate
+	Not   *SearchPredicate
+	Match *Match
+}
+
+// EqPredicate builds a single Match leaf for key contains value, the shape
+// that the legacy Tags map sugar lowers to.
+func EqPredicate(key, value string) *SearchPredicate {
+	return &SearchPredicate{Match: &Match{Key: key, Op: OpEq, Value: value}}
+}
+
+// AndPredicates combines preds with AND. A single predicate is returned
+// unwrapped, and no predicates yields nil (matches everything).
+func AndPredicates(preds ...*SearchPredicate) *SearchPredicate {
+	switch len(preds) {
+	case 0:
+		return nil
+	case 1:
+		return preds[0]
+	default:
+		return &SearchPredicate{And: preds}
+	}
+}
+
+// PredicateFromTags lowers the legacy SearchRequest.Tags map into an AND of
+// Eq matches, preserving the existing substring-equality search semantics
+// for callers that haven't adopted the structured predicate tree.
+func PredicateFromTags(tags map[string]string) *SearchPredicate {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	preds := make([]*SearchPredicate, 0, len(tags))
+	for k, v := range tags {
+		preds = append(preds, EqPredicate(k, v))
+	}
+
+	return AndPredicates(preds...)
+}
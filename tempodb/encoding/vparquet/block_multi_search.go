@@ -0,0 +1,168 @@
+package vparquet
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// MultiBlockSearcher fans a single SearchRequest out across many blocks. It
+// collects each block's results as that block finishes and stops starting
+// new blocks once req.Limit traces have been found. Results are not streamed
+// to the caller: Search returns them all in one SearchResponse.
+type MultiBlockSearcher struct {
+	blocks []*backendBlock
+}
+
+// NewMultiBlockSearcher builds a searcher over blocks, ordered newest-first
+// by BlockMeta.EndTime. For a typical "most recent traces matching X" query
+// this means the scheduler finds a satisfying Limit of results, and cancels
+// the rest, well before it has opened every block.
+func NewMultiBlockSearcher(blocks []*backendBlock) *MultiBlockSearcher {
+	sorted := make([]*backendBlock, len(blocks))
+	copy(sorted, blocks)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].meta.EndTime.After(sorted[j].meta.EndTime)
+	})
+
+	return &MultiBlockSearcher{blocks: sorted}
+}
+
+// Search runs req against the searcher's blocks using up to
+// opts.Concurrency workers. A block whose BlockMeta time range can't
+// possibly overlap req.Start/req.End is skipped before any parquet file is
+// opened. Once req.Limit traces have been found, no further blocks are
+// started and the context of in-flight workers is cancelled; Search still
+// waits for those workers to return before it returns. Per-block
+// common.SearchMetrics are merged into opts.Metrics, if set.
+func (m *MultiBlockSearcher) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	candidates := make([]*backendBlock, 0, len(m.blocks))
+	for _, b := range m.blocks {
+		if blockMetaOverlaps(b.meta.StartTime, b.meta.EndTime, req.Start, req.End) {
+			candidates = append(candidates, b)
+		}
+	}
+
+	var (
+		mu           sync.Mutex
+		traces       []*tempopb.TraceSearchMetadata
+		firstErr     error
+		limitReached bool
+	)
+
+	// stopDispatching reports whether the scheduler should avoid starting
+	// any more block searches: either because enough results are already
+	// guaranteed, a block has already failed, or the caller's own ctx died.
+	// Checking this immediately before a block's Search call (not just at
+	// the top of the dispatch loop below) caps how many blocks can be
+	// opened after the limit is met to roughly the in-flight batch, instead
+	// of however many blocks happen to finish faster than the matching one.
+	stopDispatching := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return limitReached || firstErr != nil || ctx.Err() != nil
+	}
+
+	wg := boundedwaitgroup.New(uint(concurrency))
+
+	for _, b := range candidates {
+		if stopDispatching() {
+			break
+		}
+
+		b := b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if stopDispatching() {
+				return
+			}
+
+			mu.Lock()
+			opts.Metrics.AddBlocksOpened(1)
+			mu.Unlock()
+
+			blockOpts := opts
+			var blockMetrics *common.SearchMetrics
+			if opts.Metrics != nil {
+				blockMetrics = common.NewSearchMetrics()
+				blockOpts.Metrics = blockMetrics
+			}
+
+			resp, err := b.Search(ctx, req, blockOpts)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if blockMetrics != nil {
+				opts.Metrics.Merge(blockMetrics)
+			}
+
+			if err != nil {
+				// Once the limit has been met, this function has cancelled
+				// ctx itself, so any error from then on is dropped. Before
+				// that, the first error is kept and returned, including one
+				// caused by the caller's own context being cancelled or
+				// timing out.
+				if !limitReached && firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+
+			traces = append(traces, resp.Traces...)
+			if req.Limit > 0 && uint32(len(traces)) >= req.Limit {
+				limitReached = true
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if req.Limit > 0 && uint32(len(traces)) > req.Limit {
+		traces = traces[:req.Limit]
+	}
+
+	return &tempopb.SearchResponse{
+		Traces:  traces,
+		Metrics: &tempopb.SearchMetrics{},
+	}, nil
+}
+
+// blockMetaOverlaps reports whether a block spanning [blockStart, blockEnd]
+// could contain a trace for a request restricted to [reqStart, reqEnd].
+// Either bound may be zero, meaning that side of the request's time-range
+// filter is open-ended, the same as rowGroup.overlaps treats a zero Start
+// or End for the single-block path.
+func blockMetaOverlaps(blockStart, blockEnd time.Time, reqStart, reqEnd uint32) bool {
+	if reqStart != 0 && blockEnd.Before(time.Unix(int64(reqStart), 0)) {
+		return false
+	}
+	if reqEnd != 0 && blockStart.After(time.Unix(int64(reqEnd), 0)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package vparquet
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMultiBlockSearcher builds a handful of blocks, drops the trace we're
+// looking for into one of them, and checks that the scheduler both finds it
+// and stops well short of opening every block once Limit is satisfied.
+func TestMultiBlockSearcher(t *testing.T) {
+	const (
+		numBlocks   = 16
+		concurrency = 4
+	)
+
+	wantTr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{ServiceName: "myservice"},
+				InstrumentationLibrarySpans: []ILS{
+					{Spans: []Span{{Name: "hello"}}},
+				},
+			},
+		},
+	}
+	insertAt := rand.Intn(numBlocks)
+
+	// Every block but insertAt is loaded with far more traces than the
+	// wantTr block, so its Search call reliably takes longer to complete.
+	// Without this gradient, all blocks finish at roughly the same
+	// (near-instant) speed and which ones happen to free their scheduler
+	// slot before cancellation propagates is a coin flip, which is what
+	// made the tighter bound below flaky in the first place.
+	const otherBlockTraces = 500
+
+	blocks := make([]*backendBlock, 0, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		var trs []*Trace
+		if i == insertAt {
+			trs = []*Trace{wantTr}
+		} else {
+			trs = make([]*Trace, 0, otherBlockTraces)
+			for j := 0; j < otherBlockTraces; j++ {
+				id := test.ValidTraceID(nil)
+				pbTrace := test.MakeTrace(10, id)
+				pqTrace := traceToParquet(id, pbTrace)
+				trs = append(trs, &pqTrace)
+			}
+		}
+
+		b := makeBackendBlockWithTraces(t, trs)
+		// Newest-first ordering is keyed off BlockMeta.EndTime; the block
+		// holding wantTr is made the newest so it's scheduled first.
+		if i == insertAt {
+			b.meta.EndTime = time.Now()
+		} else {
+			b.meta.EndTime = time.Now().Add(-time.Duration(i+1) * time.Hour)
+		}
+		blocks = append(blocks, b)
+	}
+
+	searcher := NewMultiBlockSearcher(blocks)
+
+	metrics := common.NewSearchMetrics()
+	opts := defaultSearchOptions()
+	opts.Metrics = metrics
+	opts.Concurrency = concurrency
+
+	req := &tempopb.SearchRequest{
+		Tags:  map[string]string{"service.name": "myservice"},
+		Limit: 1,
+	}
+
+	res, err := searcher.Search(context.Background(), req, opts)
+	require.NoError(t, err)
+
+	wantID := util.TraceIDToHexString(wantTr.TraceID)
+	var found bool
+	for _, tr := range res.Traces {
+		if tr.TraceID == wantID {
+			found = true
+		}
+	}
+	require.True(t, found, "expected to find wantTr among results")
+
+	// The newest block (wantTr's, trivially small) is scheduled first and
+	// satisfies Limit well before any of the much larger remaining blocks
+	// finish searching, so the scheduler should never dispatch past the
+	// initial bounded-concurrency batch.
+	require.Equal(t, concurrency, metrics.BlocksOpened)
+}
+
+func TestBlockMetaOverlaps(t *testing.T) {
+	blockStart := time.Unix(1000, 0)
+	blockEnd := time.Unix(2000, 0)
+
+	tests := []struct {
+		name           string
+		reqStart       uint32
+		reqEnd         uint32
+		wantOverlapped bool
+	}{
+		{"no filter", 0, 0, true},
+		{"fully contains block", 500, 2500, true},
+		{"open-ended start, end within block", 0, 1500, true},
+		{"open-ended start, end before block", 0, 500, false},
+		{"open-ended end, start within block", 1500, 0, true},
+		{"open-ended end, start after block", 2500, 0, false},
+		{"disjoint before block", 1, 999, false},
+		{"disjoint after block", 2001, 3000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := blockMetaOverlaps(blockStart, blockEnd, tt.reqStart, tt.reqEnd)
+			require.Equal(t, tt.wantOverlapped, got)
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package vparquet
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// compiledPredicate mirrors the shape of a common.SearchPredicate tree, but
+// with any OpRegex/OpNotRegex leaves compiled once up front so that a single
+// Search call never recompiles the same regular expression per row group.
+type compiledPredicate struct {
+	and   []*compiledPredicate
+	or    []*compiledPredicate
+	not   *compiledPredicate
+	match *compiledMatch
+}
+
+type compiledMatch struct {
+	key   string
+	op    common.Op
+	value string
+	re    *regexp.Regexp
+}
+
+// compilePredicate compiles pred into a compiledPredicate of the same shape.
+// A nil pred compiles to nil, which matches everything. Choosing between
+// opts.Predicate and the lowered Tags map is done by the caller (Search).
+func compilePredicate(pred *common.SearchPredicate) (*compiledPredicate, error) {
+	if pred == nil {
+		return nil, nil
+	}
+
+	cp := &compiledPredicate{}
+
+	switch {
+	case pred.Match != nil:
+		cm := &compiledMatch{
+			key:   pred.Match.Key,
+			op:    pred.Match.Op,
+			value: pred.Match.Value,
+		}
+
+		if cm.op == common.OpRegex || cm.op == common.OpNotRegex {
+			re, err := regexp.Compile(cm.value)
+			if err != nil {
+				return nil, fmt.Errorf("compiling regex for tag %q: %w", cm.key, err)
+			}
+			cm.re = re
+		}
+
+		cp.match = cm
+
+	case pred.Not != nil:
+		child, err := compilePredicate(pred.Not)
+		if err != nil {
+			return nil, err
+		}
+		cp.not = child
+
+	case len(pred.And) > 0:
+		for _, child := range pred.And {
+			cc, err := compilePredicate(child)
+			if err != nil {
+				return nil, err
+			}
+			cp.and = append(cp.and, cc)
+		}
+
+	case len(pred.Or) > 0:
+		for _, child := range pred.Or {
+			cc, err := compilePredicate(child)
+			if err != nil {
+				return nil, err
+			}
+			cp.or = append(cp.or, cc)
+		}
+	}
+
+	return cp, nil
+}
+
+// matches evaluates the compiled predicate tree against a single row's tag
+// values, as resolved by lookup.
+func (cp *compiledPredicate) matches(lookup func(key string) (string, bool)) bool {
+	if cp == nil {
+		return true
+	}
+
+	switch {
+	case cp.match != nil:
+		return cp.match.matches(lookup)
+
+	case cp.not != nil:
+		return !cp.not.matches(lookup)
+
+	case len(cp.and) > 0:
+		for _, child := range cp.and {
+			if !child.matches(lookup) {
+				return false
+			}
+		}
+		return true
+
+	case len(cp.or) > 0:
+		for _, child := range cp.or {
+			if child.matches(lookup) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func (cm *compiledMatch) matches(lookup func(key string) (string, bool)) bool {
+	val, ok := lookup(cm.key)
+
+	switch cm.op {
+	case common.OpEq:
+		// Matches the legacy Tags map semantics: a case-sensitive substring
+		// match, not an exact equality check.
+		return ok && strings.Contains(val, cm.value)
+	case common.OpNotEq:
+		return !ok || !strings.Contains(val, cm.value)
+	case common.OpRegex:
+		return ok && cm.re.MatchString(val)
+	case common.OpNotRegex:
+		return !ok || !cm.re.MatchString(val)
+	case common.OpGreaterThan:
+		return ok && compareNumeric(val, cm.value) > 0
+	case common.OpLessThan:
+		return ok && compareNumeric(val, cm.value) < 0
+	}
+
+	return false
+}
+
+// matchCount counts every Match leaf in the predicate tree, regardless of
+// how it's combined. It's used to report a meaningful tag count for queries
+// driven by the structured predicate tree rather than the legacy Tags map.
+func (cp *compiledPredicate) matchCount() int {
+	if cp == nil {
+		return 0
+	}
+
+	switch {
+	case cp.match != nil:
+		return 1
+	case cp.not != nil:
+		return cp.not.matchCount()
+	default:
+		n := 0
+		for _, child := range cp.and {
+			n += child.matchCount()
+		}
+		for _, child := range cp.or {
+			n += child.matchCount()
+		}
+		return n
+	}
+}
+
+// eqTags collects the Eq leaves that every matching row must satisfy: ones
+// reachable from the root through And nodes only. A leaf under an Or is
+// skipped, since no single one of them is required for a match; a leaf
+// under a Not is skipped too, since bloom filters can only answer "is this
+// value definitely absent", which isn't useful for a negation. The result
+// is meant to be merged into the legacy Tags-based bloom check so that
+// Eq leaves expressed via the structured predicate still prune blocks.
+func (cp *compiledPredicate) eqTags() map[string]string {
+	tags := make(map[string]string)
+	cp.collectEqTags(tags)
+	return tags
+}
+
+func (cp *compiledPredicate) collectEqTags(tags map[string]string) {
+	if cp == nil {
+		return
+	}
+
+	switch {
+	case cp.match != nil:
+		if cp.match.op == common.OpEq {
+			tags[cp.match.key] = cp.match.value
+		}
+	case len(cp.and) > 0:
+		for _, child := range cp.and {
+			child.collectEqTags(tags)
+		}
+	}
+}
+
+// excludesRowGroup reports whether some negation reachable from cp can be
+// resolved purely from a row group's column dictionary: if the dictionary
+// contains only the excluded value, every row in the group fails that
+// negation and the group can be dropped outright without a full column
+// scan. It recurses through And/Or so the check fires for the predicate
+// shapes a real query actually produces, not just a bare negation at the
+// root: a negation under an And excludes the whole group (And needs every
+// leaf to hold, including the negation), and a negation under an Or only
+// excludes the group if every other Or branch is excluded too (Or needs
+// just one leaf to hold).
+func (cp *compiledPredicate) excludesRowGroup(dictionaryValues func(key string) []string) bool {
+	if cp == nil {
+		return false
+	}
+
+	switch {
+	case cp.match != nil:
+		return matchExcludesRowGroup(cp.match, dictionaryValues)
+
+	case cp.not != nil:
+		return notExcludesRowGroup(cp.not, dictionaryValues)
+
+	case len(cp.and) > 0:
+		for _, child := range cp.and {
+			if child.excludesRowGroup(dictionaryValues) {
+				return true
+			}
+		}
+		return false
+
+	case len(cp.or) > 0:
+		for _, child := range cp.or {
+			if !child.excludesRowGroup(dictionaryValues) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// notExcludesRowGroup handles a Not node: only Not(Eq) is resolvable from
+// the dictionary alone, the same way an OpNotEq leaf is in
+// matchExcludesRowGroup.
+func notExcludesRowGroup(negated *compiledPredicate, dictionaryValues func(key string) []string) bool {
+	if negated == nil || negated.match == nil || negated.match.op != common.OpEq {
+		return false
+	}
+
+	return dictionaryExcludes(negated.match, dictionaryValues)
+}
+
+// matchExcludesRowGroup handles a bare OpNotEq leaf the same way
+// notExcludesRowGroup handles a Not(Eq) leaf: both mean "every row must not
+// equal this value".
+func matchExcludesRowGroup(m *compiledMatch, dictionaryValues func(key string) []string) bool {
+	if m.op != common.OpNotEq {
+		return false
+	}
+
+	return dictionaryExcludes(m, dictionaryValues)
+}
+
+// dictionaryExcludes reports whether m's dictionary holds only the value
+// being excluded, so every row in the group fails the negation.
+func dictionaryExcludes(m *compiledMatch, dictionaryValues func(key string) []string) bool {
+	values := dictionaryValues(m.key)
+	if len(values) != 1 {
+		return false
+	}
+
+	// Eq is a substring match, so the group is only fully excluded if the
+	// dictionary's one distinct value would itself satisfy the Eq leaf.
+	return strings.Contains(values[0], m.value)
+}
+
+// compareNumeric compares a and b as floats when both parse cleanly,
+// falling back to a lexical comparison for columns that aren't numeric.
+func compareNumeric(a, b string) int {
+	af, aerr := strconv.ParseFloat(a, 64)
+	bf, berr := strconv.ParseFloat(b, 64)
+
+	if aerr == nil && berr == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
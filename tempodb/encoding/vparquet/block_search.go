@@ -0,0 +1,262 @@
+package vparquet
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var tracer = otel.Tracer("tempodb/encoding/vparquet")
+
+// Search opens the backend block's parquet file and walks its row groups
+// looking for traces that satisfy req. The work is broken into the same
+// stages the backend actually performs (open, bloom check, row group
+// pruning, column reads, predicate evaluation) so that each stage can be
+// measured independently when investigating a slow query.
+func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
+	ctx, span := tracer.Start(ctx, "vparquet.Search")
+	defer span.End()
+
+	pred := opts.Predicate
+	if pred == nil {
+		pred = common.PredicateFromTags(req.Tags)
+	}
+
+	compiled, err := compilePredicate(pred)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("blockID", b.meta.BlockID.String()),
+		attribute.String("tenantID", b.meta.TenantID),
+		// tagCount reflects however the query expressed its matches: the
+		// legacy Tags map or the structured Predicate tree, both of which
+		// are folded into compiled above.
+		attribute.Int("tagCount", compiled.matchCount()),
+		attribute.Int64("minDurationMs", int64(req.MinDurationMs)),
+		attribute.Int64("maxDurationMs", int64(req.MaxDurationMs)),
+		attribute.Int64("start", int64(req.Start)),
+		attribute.Int64("end", int64(req.End)),
+		attribute.Int64("chunkSizeBytes", int64(opts.ChunkSizeBytes)),
+		attribute.Int("readBufferCount", opts.ReadBufferCount),
+		attribute.Int("readBufferSize", opts.ReadBufferSize),
+	)
+
+	pf, err := b.openParquetFile(ctx, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	// metrics accumulates stage durations for this call regardless of
+	// whether the caller asked for a breakdown, so that Prometheus
+	// observability doesn't depend on every call site opting in via
+	// opts.Metrics. It is folded into the caller's SearchMetrics below.
+	metrics := common.NewSearchMetrics()
+
+	rowGroups, err := b.pruneRowGroups(ctx, pf, req, compiled, metrics)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	resp := &tempopb.SearchResponse{
+		Metrics: &tempopb.SearchMetrics{},
+	}
+
+	for _, rg := range rowGroups {
+		if !b.bloomCheck(ctx, rg, req, compiled, metrics) {
+			continue
+		}
+
+		cols, err := b.readColumns(ctx, rg, req, metrics)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+
+		matches, err := b.evaluatePredicate(ctx, cols, compiled, req.MinDurationMs, req.MaxDurationMs, req.Start, req.End, metrics)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+
+		resp.Traces = append(resp.Traces, matches...)
+	}
+
+	recordSearchMetrics(b.meta.TenantID, compiled.matchCount(), metrics)
+
+	if opts.Metrics != nil {
+		opts.Metrics.Merge(metrics)
+	}
+
+	return resp, nil
+}
+
+// openParquetFile opens the backend's parquet object for this block, using
+// the read buffer settings from opts to size the backend reads.
+func (b *backendBlock) openParquetFile(ctx context.Context, opts common.SearchOptions) (*parquetFile, error) {
+	_, span := tracer.Start(ctx, "vparquet.OpenParquet")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("readBufferCount", opts.ReadBufferCount),
+		attribute.Int("readBufferSize", opts.ReadBufferSize),
+	)
+
+	pf, err := openBackendParquetFile(ctx, b.r, b.meta, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return pf, nil
+}
+
+// bloomCheck consults the block's bloom filter shard for the tags present in
+// req, plus any Eq leaves of pred that every matching row must satisfy,
+// before any row-group data is read. A negative result means the trace
+// cannot be in this row group and the row group is skipped entirely.
+func (b *backendBlock) bloomCheck(ctx context.Context, rg *rowGroup, req *tempopb.SearchRequest, pred *compiledPredicate, metrics *common.SearchMetrics) bool {
+	start := time.Now()
+	_, span := tracer.Start(ctx, "vparquet.BloomCheck")
+	defer span.End()
+
+	hit := rg.bloomMightContain(bloomRequest(req, pred))
+
+	metrics.AddStageDuration(common.StageBloomCheck, time.Since(start))
+	metrics.AddBloomCheck(hit)
+
+	span.SetAttributes(attribute.Bool("hit", hit))
+
+	return hit
+}
+
+// bloomRequest merges pred's required Eq leaves into req's Tags so that the
+// bloom check still prunes on them when the caller used opts.Predicate
+// instead of (or in addition to) the legacy Tags map.
+func bloomRequest(req *tempopb.SearchRequest, pred *compiledPredicate) *tempopb.SearchRequest {
+	eqTags := pred.eqTags()
+	if len(eqTags) == 0 {
+		return req
+	}
+
+	merged := make(map[string]string, len(req.Tags)+len(eqTags))
+	for k, v := range req.Tags {
+		merged[k] = v
+	}
+	for k, v := range eqTags {
+		merged[k] = v
+	}
+
+	return &tempopb.SearchRequest{
+		Tags:          merged,
+		MinDurationMs: req.MinDurationMs,
+		MaxDurationMs: req.MaxDurationMs,
+		Start:         req.Start,
+		End:           req.End,
+		Limit:         req.Limit,
+	}
+}
+
+// pruneRowGroups filters the file's row groups down to the ones that can
+// possibly match: their min/max time-range statistics must overlap the
+// request's Start/End window, and a Not(Eq) leaf whose excluded value is the
+// dictionary's only value rules the whole group out without a column scan.
+func (b *backendBlock) pruneRowGroups(ctx context.Context, pf *parquetFile, req *tempopb.SearchRequest, pred *compiledPredicate, metrics *common.SearchMetrics) ([]*rowGroup, error) {
+	start := time.Now()
+	_, span := tracer.Start(ctx, "vparquet.RowGroupPrune")
+	defer span.End()
+
+	all := pf.RowGroups()
+	kept := make([]*rowGroup, 0, len(all))
+
+	for _, rg := range all {
+		if !rg.overlaps(req.Start, req.End) {
+			continue
+		}
+		if pred.excludesRowGroup(rg.dictionaryValues) {
+			continue
+		}
+		kept = append(kept, rg)
+	}
+
+	metrics.AddStageDuration(common.StageRowGroupPrune, time.Since(start))
+	metrics.AddRowGroups(len(all), len(kept))
+
+	span.SetAttributes(
+		attribute.Int("rowGroupsTotal", len(all)),
+		attribute.Int("rowGroupsKept", len(kept)),
+	)
+
+	return kept, nil
+}
+
+// readColumns fetches the column pages needed to evaluate req's predicate
+// out of the backend, respecting the block's configured chunk size.
+func (b *backendBlock) readColumns(ctx context.Context, rg *rowGroup, req *tempopb.SearchRequest, metrics *common.SearchMetrics) (*columnSet, error) {
+	start := time.Now()
+	_, span := tracer.Start(ctx, "vparquet.ColumnRead")
+	defer span.End()
+
+	cols, err := rg.readColumnsFor(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	metrics.AddStageDuration(common.StageColumnRead, time.Since(start))
+	metrics.AddColumnRead(cols.bytesRead, cols.pagesRead)
+
+	span.SetAttributes(
+		attribute.Int64("bytesRead", cols.bytesRead),
+		attribute.Int("pagesRead", cols.pagesRead),
+		attribute.Bool("dictionaryOnly", cols.dictionaryOnly),
+	)
+
+	return cols, nil
+}
+
+// evaluatePredicate runs the compiled predicate tree against the column data
+// fetched for a row group and returns the traces that satisfy it.
+func (b *backendBlock) evaluatePredicate(ctx context.Context, cols *columnSet, pred *compiledPredicate, minDurationMs, maxDurationMs, start, end uint32, metrics *common.SearchMetrics) ([]*tempopb.TraceSearchMetadata, error) {
+	stageStart := time.Now()
+	_, span := tracer.Start(ctx, "vparquet.Predicate")
+	defer span.End()
+
+	rowsEvaluated, matches := cols.matchPredicate(pred, minDurationMs, maxDurationMs, start, end)
+
+	metrics.AddStageDuration(common.StagePredicate, time.Since(stageStart))
+	metrics.AddPredicateEvaluations(rowsEvaluated, len(matches))
+
+	results := make([]*tempopb.TraceSearchMetadata, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, &tempopb.TraceSearchMetadata{
+			TraceID:           util.TraceIDToHexString(m.TraceID),
+			StartTimeUnixNano: m.StartTimeUnixNano,
+			DurationMs:        m.DurationMs,
+			RootServiceName:   m.RootServiceName,
+			RootTraceName:     m.RootTraceName,
+		})
+	}
+
+	span.SetAttributes(attribute.Int("matchingRows", len(results)))
+
+	return results, nil
+}
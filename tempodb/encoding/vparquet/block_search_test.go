@@ -16,6 +16,11 @@ import (
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestBackendBlockSearch(t *testing.T) {
@@ -218,6 +223,364 @@ func TestBackendBlockSearch(t *testing.T) {
 	}
 }
 
+// TestBackendBlockSearchPredicate expands on TestBackendBlockSearch to cover
+// the structured common.SearchPredicate tree: negation, regex and OR
+// semantics on top of the existing AND-of-equals Tags sugar.
+func TestBackendBlockSearchPredicate(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	intPtr := func(i int64) *int64 { return &i }
+
+	wantTr := &Trace{
+		TraceID:         test.ValidTraceID(nil),
+		RootServiceName: "RootService",
+		RootSpanName:    "RootSpan",
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{
+					ServiceName: "payments-api",
+				},
+				InstrumentationLibrarySpans: []ILS{
+					{
+						Spans: []Span{
+							{
+								Name:           "hello",
+								HttpMethod:     strPtr("get"),
+								HttpStatusCode: intPtr(500),
+								StatusCode:     int(v1.Status_STATUS_CODE_ERROR),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b := makeBackendBlockWithTraces(t, []*Trace{wantTr})
+	ctx := context.TODO()
+
+	findInResults := func(id string, res []*tempopb.TraceSearchMetadata) *tempopb.TraceSearchMetadata {
+		for _, r := range res {
+			if r.TraceID == id {
+				return r
+			}
+		}
+		return nil
+	}
+
+	traceIDHex := util.TraceIDToHexString(wantTr.TraceID)
+
+	predicatesThatMatch := []*common.SearchPredicate{
+		// status.code=error AND http.method!=post
+		{
+			And: []*common.SearchPredicate{
+				{Match: &common.Match{Key: StatusCodeTag, Op: common.OpEq, Value: StatusCodeError}},
+				{Match: &common.Match{Key: LabelHTTPMethod, Op: common.OpNotEq, Value: "post"}},
+			},
+		},
+		// service.name=~"payments-.*" OR service.name=cart
+		{
+			Or: []*common.SearchPredicate{
+				{Match: &common.Match{Key: LabelServiceName, Op: common.OpRegex, Value: "payments-.*"}},
+				{Match: &common.Match{Key: LabelServiceName, Op: common.OpEq, Value: "cart"}},
+			},
+		},
+		// http.status_code > 199
+		{Match: &common.Match{Key: LabelHTTPStatusCode, Op: common.OpGreaterThan, Value: "199"}},
+		// NOT http.method=post
+		{Not: &common.SearchPredicate{Match: &common.Match{Key: LabelHTTPMethod, Op: common.OpEq, Value: "post"}}},
+	}
+
+	for _, pred := range predicatesThatMatch {
+		opts := defaultSearchOptions()
+		opts.Predicate = pred
+
+		res, err := b.Search(ctx, &tempopb.SearchRequest{}, opts)
+		require.NoError(t, err)
+
+		meta := findInResults(traceIDHex, res.Traces)
+		require.NotNilf(t, meta, "predicate should match: %+v", pred)
+	}
+
+	predicatesThatDontMatch := []*common.SearchPredicate{
+		// http.method!=get
+		{Match: &common.Match{Key: LabelHTTPMethod, Op: common.OpNotEq, Value: "get"}},
+		// service.name=~"cart-.*"
+		{Match: &common.Match{Key: LabelServiceName, Op: common.OpRegex, Value: "cart-.*"}},
+		// http.status_code < 500
+		{Match: &common.Match{Key: LabelHTTPStatusCode, Op: common.OpLessThan, Value: "500"}},
+		// status.code=error AND NOT service.name=~"payments-.*" (excludes via Not on a resource-level attribute)
+		{
+			And: []*common.SearchPredicate{
+				{Match: &common.Match{Key: StatusCodeTag, Op: common.OpEq, Value: StatusCodeError}},
+				{Not: &common.SearchPredicate{Match: &common.Match{Key: LabelServiceName, Op: common.OpRegex, Value: "payments-.*"}}},
+			},
+		},
+	}
+
+	for _, pred := range predicatesThatDontMatch {
+		opts := defaultSearchOptions()
+		opts.Predicate = pred
+
+		res, err := b.Search(ctx, &tempopb.SearchRequest{}, opts)
+		require.NoError(t, err)
+
+		meta := findInResults(traceIDHex, res.Traces)
+		require.Nilf(t, meta, "predicate should not match: %+v", pred)
+	}
+}
+
+// TestBackendBlockSearchPredicateRowGroupPruning asserts that a negation
+// whose excluded value is a row group's only dictionary value prunes that
+// row group without a column scan, for the predicate shapes excludesRowGroup
+// is meant to handle: a bare OpNotEq leaf, one under an And (the backlog's
+// own "status.code=error AND http.method!=GET" example), and a Not(Eq) leaf
+// under an Or where every branch is excluded.
+func TestBackendBlockSearchPredicateRowGroupPruning(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	const total = 50
+	traces := make([]*Trace, 0, total)
+	for i := 0; i < total; i++ {
+		traces = append(traces, &Trace{
+			TraceID: test.ValidTraceID(nil),
+			ResourceSpans: []ResourceSpans{
+				{
+					Resource: Resource{ServiceName: "myservice"},
+					InstrumentationLibrarySpans: []ILS{
+						{
+							Spans: []Span{
+								{
+									Name:       "hello",
+									HttpMethod: strPtr("get"),
+									StatusCode: int(v1.Status_STATUS_CODE_ERROR),
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	b := makeBackendBlockWithTraces(t, traces)
+	ctx := context.TODO()
+
+	tests := []*common.SearchPredicate{
+		// http.method!=get
+		{Match: &common.Match{Key: LabelHTTPMethod, Op: common.OpNotEq, Value: "get"}},
+		// status.code=error AND http.method!=get
+		{
+			And: []*common.SearchPredicate{
+				{Match: &common.Match{Key: StatusCodeTag, Op: common.OpEq, Value: StatusCodeError}},
+				{Match: &common.Match{Key: LabelHTTPMethod, Op: common.OpNotEq, Value: "get"}},
+			},
+		},
+		// NOT http.method=get OR NOT status.code=error
+		{
+			Or: []*common.SearchPredicate{
+				{Not: &common.SearchPredicate{Match: &common.Match{Key: LabelHTTPMethod, Op: common.OpEq, Value: "get"}}},
+				{Not: &common.SearchPredicate{Match: &common.Match{Key: StatusCodeTag, Op: common.OpEq, Value: StatusCodeError}}},
+			},
+		},
+	}
+
+	for _, pred := range tests {
+		metrics := common.NewSearchMetrics()
+		opts := defaultSearchOptions()
+		opts.Metrics = metrics
+		opts.Predicate = pred
+
+		res, err := b.Search(ctx, &tempopb.SearchRequest{}, opts)
+		require.NoError(t, err)
+		require.Emptyf(t, res.Traces, "predicate should not match: %+v", pred)
+
+		require.NotZerof(t, metrics.RowGroupsTotal, "predicate: %+v", pred)
+		require.Zerof(t, metrics.RowGroupsInspected, "dictionary pruning should drop every row group for predicate: %+v", pred)
+	}
+}
+
+// TestBackendBlockSearchMetrics asserts that a Search call populates the
+// common.SearchMetrics passed in via SearchOptions, and that a time-range
+// filter narrow enough to prune row groups actually inspects fewer than the
+// block's total.
+func TestBackendBlockSearchMetrics(t *testing.T) {
+	wantTr := &Trace{
+		TraceID:           test.ValidTraceID(nil),
+		StartTimeUnixNano: uint64(1000 * time.Second),
+		EndTimeUnixNano:   uint64(2000 * time.Second),
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{ServiceName: "myservice"},
+				InstrumentationLibrarySpans: []ILS{
+					{Spans: []Span{{Name: "hello"}}},
+				},
+			},
+		},
+	}
+
+	total := 1000
+	insertAt := rand.Intn(total)
+	allTraces := make([]*Trace, 0, total)
+	for i := 0; i < total; i++ {
+		if i == insertAt {
+			allTraces = append(allTraces, wantTr)
+			continue
+		}
+		id := test.ValidTraceID(nil)
+		pbTrace := test.MakeTrace(10, id)
+		pqTrace := traceToParquet(id, pbTrace)
+		allTraces = append(allTraces, &pqTrace)
+	}
+
+	b := makeBackendBlockWithTraces(t, allTraces)
+	ctx := context.TODO()
+
+	metrics := common.NewSearchMetrics()
+	opts := defaultSearchOptions()
+	opts.Metrics = metrics
+
+	req := &tempopb.SearchRequest{
+		Tags: map[string]string{"service.name": "myservice"},
+		// narrow enough to not span the whole block's time range
+		Start: 1000,
+		End:   1001,
+	}
+
+	_, err := b.Search(ctx, req, opts)
+	require.NoError(t, err)
+
+	require.NotZero(t, metrics.BloomChecks)
+	require.NotZero(t, metrics.ColumnBytesRead)
+	require.NotZero(t, metrics.PagesRead)
+	require.NotZero(t, metrics.PredicateEvaluations)
+	require.NotZero(t, metrics.PredicateMatches)
+	require.Less(t, metrics.RowGroupsInspected, metrics.RowGroupsTotal)
+}
+
+// TestBackendBlockSearchNilMetrics guards against the counter-accumulating
+// stages panicking on a nil *common.SearchMetrics, which is what
+// defaultSearchOptions (and any caller that doesn't care about metrics)
+// leaves opts.Metrics as.
+func TestBackendBlockSearchNilMetrics(t *testing.T) {
+	wantTr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{ServiceName: "myservice"},
+				InstrumentationLibrarySpans: []ILS{
+					{Spans: []Span{{Name: "hello"}}},
+				},
+			},
+		},
+	}
+
+	b := makeBackendBlockWithTraces(t, []*Trace{wantTr})
+	ctx := context.TODO()
+
+	opts := defaultSearchOptions()
+	require.Nil(t, opts.Metrics)
+
+	before := sampleCount(t, b.meta.TenantID, string(common.StageBloomCheck), "1-2")
+
+	require.NotPanics(t, func() {
+		_, err := b.Search(ctx, &tempopb.SearchRequest{
+			Tags: map[string]string{"service.name": "myservice"},
+		}, opts)
+		require.NoError(t, err)
+	})
+
+	// Prometheus observability must not depend on the caller opting in via
+	// opts.Metrics.
+	after := sampleCount(t, b.meta.TenantID, string(common.StageBloomCheck), "1-2")
+	require.Greater(t, after, before)
+}
+
+func TestBackendBlockSearchTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	wantTr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{
+					ServiceName: "myservice",
+				},
+				InstrumentationLibrarySpans: []ILS{
+					{
+						Spans: []Span{
+							{
+								Name:           "hello",
+								HttpMethod:     func() *string { s := "get"; return &s }(),
+								HttpStatusCode: func() *int64 { i := int64(500); return &i }(),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b := makeBackendBlockWithTraces(t, []*Trace{wantTr})
+	ctx := context.TODO()
+
+	req := &tempopb.SearchRequest{
+		Tags: map[string]string{
+			"service.name": "myservice",
+			"http.method":  "get",
+		},
+	}
+
+	_, err := b.Search(ctx, req, defaultSearchOptions())
+	require.NoError(t, err)
+	require.NoError(t, tp.ForceFlush(ctx))
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	for _, name := range []string{
+		"vparquet.Search",
+		"vparquet.OpenParquet",
+		"vparquet.RowGroupPrune",
+		"vparquet.BloomCheck",
+		"vparquet.ColumnRead",
+		"vparquet.Predicate",
+	} {
+		require.Containsf(t, byName, name, "expected span %q to be emitted", name)
+	}
+
+	root := byName["vparquet.Search"]
+	attrs := attrMap(root.Attributes)
+	require.Equal(t, b.meta.BlockID.String(), attrs["blockID"].AsString())
+	require.Equal(t, int64(len(req.Tags)), attrs["tagCount"].AsInt64())
+
+	prune := byName["vparquet.RowGroupPrune"]
+	pruneAttrs := attrMap(prune.Attributes)
+	require.Contains(t, pruneAttrs, "rowGroupsTotal")
+	require.Contains(t, pruneAttrs, "rowGroupsKept")
+
+	// every non-root span should be parented under the vparquet.Search span
+	for _, s := range spans {
+		if s.Name == "vparquet.Search" {
+			continue
+		}
+		require.Equal(t, root.SpanContext.SpanID(), s.Parent.SpanID(), "span %q should be a child of vparquet.Search", s.Name)
+	}
+}
+
+func attrMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(attrs))
+	for _, a := range attrs {
+		m[a.Key] = a.Value
+	}
+	return m
+}
+
 func makeBackendBlockWithTraces(t *testing.T, trs []*Trace) *backendBlock {
 
 	rawR, rawW, _, err := local.New(&local.Config{
@@ -262,3 +625,42 @@ func defaultSearchOptions() common.SearchOptions {
 		ReadBufferSize:  4 * 1024 * 1024,
 	}
 }
+
+// TestBloomRequest checks that the Eq leaves of a compiled predicate are
+// folded into the bloom check's Tags, so that a query expressed purely via
+// opts.Predicate still gets bloom-filter pruning, and that leaves under an
+// Or or a Not are left out since they aren't safe to require unconditionally.
+func TestBloomRequest(t *testing.T) {
+	pred := &common.SearchPredicate{
+		And: []*common.SearchPredicate{
+			{Match: &common.Match{Key: "service.name", Op: common.OpEq, Value: "myservice"}},
+			{Or: []*common.SearchPredicate{
+				{Match: &common.Match{Key: "http.method", Op: common.OpEq, Value: "get"}},
+				{Match: &common.Match{Key: "http.method", Op: common.OpEq, Value: "post"}},
+			}},
+			{Not: &common.SearchPredicate{Match: &common.Match{Key: "http.status_code", Op: common.OpEq, Value: "500"}}},
+		},
+	}
+
+	compiled, err := compilePredicate(pred)
+	require.NoError(t, err)
+
+	req := &tempopb.SearchRequest{Tags: map[string]string{"env": "prod"}, Limit: 5}
+
+	merged := bloomRequest(req, compiled)
+	require.Equal(t, map[string]string{"env": "prod", "service.name": "myservice"}, merged.Tags)
+	require.Equal(t, req.Limit, merged.Limit)
+
+	// req itself isn't mutated.
+	require.Equal(t, map[string]string{"env": "prod"}, req.Tags)
+
+	// With nothing safe to push down, bloomRequest returns req unchanged.
+	orOnly, err := compilePredicate(&common.SearchPredicate{
+		Or: []*common.SearchPredicate{
+			{Match: &common.Match{Key: "http.method", Op: common.OpEq, Value: "get"}},
+			{Match: &common.Match{Key: "http.method", Op: common.OpEq, Value: "post"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Same(t, req, bloomRequest(req, orOnly))
+}
@@ -0,0 +1,50 @@
+package vparquet
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// metricSearchStageDuration tracks how long each Search stage takes,
+// broken down by tenant and a coarse tag-cardinality bucket so that a
+// handful of tags doesn't get lost in the same bucket as a query with
+// dozens of tags.
+var metricSearchStageDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "tempodb",
+		Subsystem: "vparquet",
+		Name:      "search_stage_duration_seconds",
+		Help:      "Duration of each vparquet search stage.",
+		Buckets:   prometheus.DefBuckets,
+	},
+	[]string{"tenant", "stage", "tag_cardinality"},
+)
+
+// tagCardinalityBucket groups a raw tag count into a small number of
+// labels so the duration histogram doesn't acquire a new label value per
+// distinct query shape.
+func tagCardinalityBucket(tagCount int) string {
+	switch {
+	case tagCount == 0:
+		return "0"
+	case tagCount <= 2:
+		return "1-2"
+	case tagCount <= 5:
+		return "3-5"
+	default:
+		return "6+"
+	}
+}
+
+// recordSearchMetrics publishes the stage durations accumulated in m as
+// Prometheus observations for tenant, bucketed by the number of tags in
+// the originating request.
+func recordSearchMetrics(tenant string, tagCount int, m *common.SearchMetrics) {
+	bucket := tagCardinalityBucket(tagCount)
+
+	for stage, d := range m.StageDurations {
+		metricSearchStageDuration.WithLabelValues(tenant, string(stage), bucket).Observe(d.Seconds())
+	}
+}
@@ -0,0 +1,63 @@
+package vparquet
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestTagCardinalityBucket(t *testing.T) {
+	tests := []struct {
+		tagCount int
+		want     string
+	}{
+		{0, "0"},
+		{1, "1-2"},
+		{2, "1-2"},
+		{3, "3-5"},
+		{5, "3-5"},
+		{6, "6+"},
+		{100, "6+"},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.want, tagCardinalityBucket(tc.tagCount))
+	}
+}
+
+// sampleCount reads back the number of observations recorded for the given
+// label combination of metricSearchStageDuration.
+func sampleCount(t *testing.T, tenant, stage, bucket string) uint64 {
+	t.Helper()
+
+	h, ok := metricSearchStageDuration.WithLabelValues(tenant, stage, bucket).(prometheus.Histogram)
+	require.True(t, ok)
+
+	var m dto.Metric
+	require.NoError(t, h.Write(&m))
+
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRecordSearchMetrics(t *testing.T) {
+	m := common.NewSearchMetrics()
+	m.AddStageDuration(common.StageBloomCheck, 0)
+	m.AddStageDuration(common.StageRowGroupPrune, 0)
+
+	before := sampleCount(t, "test-tenant", string(common.StageBloomCheck), "1-2")
+
+	recordSearchMetrics("test-tenant", 1, m)
+
+	after := sampleCount(t, "test-tenant", string(common.StageBloomCheck), "1-2")
+	require.Equal(t, before+1, after)
+
+	// Stages not present in m must not be observed.
+	beforeRowGroup := sampleCount(t, "test-tenant", string(common.StageRowGroupPrune), "1-2")
+	recordSearchMetrics("test-tenant", 1, common.NewSearchMetrics())
+	afterRowGroup := sampleCount(t, "test-tenant", string(common.StageRowGroupPrune), "1-2")
+	require.Equal(t, beforeRowGroup, afterRowGroup)
+}